@@ -0,0 +1,369 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// maxComposeSources mirrors the S3 multipart-upload part count limit,
+	// which bounds how many sources a single compose call can stitch together.
+	maxComposeSources = 10000
+	// maxCopyPartSize is the largest byte range a single UploadPartCopy call
+	// may cover; sources bigger than this are split into multiple copy parts.
+	maxCopyPartSize = 5 * 1024 * 1024 * 1024 // 5 GiB
+	// minCopyPartSize is S3's minimum size for every part of a multipart
+	// upload except the last one.
+	minCopyPartSize = 5 * 1024 * 1024 // 5 MiB
+)
+
+// composeSource is one entry of a /compose request body: an object (or a
+// byte range of one) to append to the destination object, optionally
+// decrypted with an SSE-C key before being copied.
+type composeSource struct {
+	Bucket               string `json:"bucket,omitempty"`
+	Key                  string `json:"key"`
+	RangeStart           *int64 `json:"rangeStart,omitempty"`
+	RangeEnd             *int64 `json:"rangeEnd,omitempty"`
+	SSECustomerAlgorithm string `json:"sseCustomerAlgorithm,omitempty"`
+	SSECustomerKey       string `json:"sseCustomerKey,omitempty"`
+	SSECustomerKeyMD5    string `json:"sseCustomerKeyMd5,omitempty"`
+}
+
+type composeRequest struct {
+	Sources []composeSource `json:"sources"`
+}
+
+type composeResponse struct {
+	ETag string `json:"etag"`
+}
+
+// Composer stitches up to maxComposeSources source objects into one
+// destination object without ever pulling their bytes through the proxy,
+// using S3 UploadPartCopy/CopyObject for AWS-compatible backends and
+// minio-go ComposeObject for MinIO.
+type Composer struct {
+	MinioClient *minio.Client
+	S3Client    *s3.Client
+}
+
+// NewComposer builds a Composer for the configured ServiceName.
+func NewComposer() (*Composer, error) {
+	if strings.EqualFold(globalConfig.ServiceName, "minio") {
+		minioClient, err := newMinioClient(legacyBackendConfig())
+		if err != nil {
+			return nil, err
+		}
+		return &Composer{MinioClient: minioClient}, nil
+	} else if strings.EqualFold(globalConfig.ServiceName, "S3") || strings.EqualFold(globalConfig.ServiceName, "R2") {
+		s3Client, err := newS3Client(legacyBackendConfig())
+		if err != nil {
+			return nil, err
+		}
+		return &Composer{S3Client: s3Client}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported object storage service")
+}
+
+// Compose writes destBucket/destKey from sources, in order.
+func (c *Composer) Compose(ctx context.Context, destBucket, destKey string, sources []composeSource) (string, error) {
+	if len(sources) == 0 {
+		return "", fmt.Errorf("compose requires at least one source")
+	}
+	if len(sources) > maxComposeSources {
+		return "", fmt.Errorf("compose supports at most %d sources, got %d", maxComposeSources, len(sources))
+	}
+
+	if c.MinioClient != nil {
+		return c.composeMinio(ctx, destBucket, destKey, sources)
+	}
+	return c.composeS3(ctx, destBucket, destKey, sources)
+}
+
+// composeMinio hands sources to minio-go ComposeObject as-is. The per-source
+// SSE-C fields are not passed on this path, so SSE-C encrypted sources are
+// not decrypted for the copy.
+func (c *Composer) composeMinio(ctx context.Context, destBucket, destKey string, sources []composeSource) (string, error) {
+	dst := minio.CopyDestOptions{Bucket: destBucket, Object: destKey}
+
+	srcs := make([]minio.CopySrcOptions, 0, len(sources))
+	for _, src := range sources {
+		bucket := src.Bucket
+		if bucket == "" {
+			bucket = destBucket
+		}
+		opts := minio.CopySrcOptions{Bucket: bucket, Object: src.Key}
+		if src.RangeStart != nil {
+			opts.MatchRange = true
+			opts.Start = *src.RangeStart
+			if src.RangeEnd != nil {
+				opts.End = *src.RangeEnd
+			}
+		}
+		srcs = append(srcs, opts)
+	}
+
+	info, err := c.MinioClient.ComposeObject(ctx, dst, srcs...)
+	if err != nil {
+		return "", fmt.Errorf("minio ComposeObject %v:%v: %w", destBucket, destKey, err)
+	}
+	return info.ETag, nil
+}
+
+// copyPart is one UploadPartCopy call's worth of work: a byte range from a
+// single source object.
+type copyPart struct {
+	bucket               string
+	key                  string
+	start, end           int64 // inclusive
+	sseCustomerAlgorithm string
+	sseCustomerKey       string
+	sseCustomerKeyMD5    string
+}
+
+func (p copyPart) size() int64 { return p.end - p.start + 1 }
+
+// planCopyParts resolves each source's byte range (stat'ing it when no
+// explicit range is given) and splits ranges over maxCopyPartSize into
+// multiple copy parts, without making any mutating calls yet.
+func planCopyParts(ctx context.Context, client *s3.Client, destBucket string, sources []composeSource) ([]copyPart, error) {
+	var parts []copyPart
+	for _, src := range sources {
+		bucket := src.Bucket
+		if bucket == "" {
+			bucket = destBucket
+		}
+
+		start := int64(0)
+		if src.RangeStart != nil {
+			start = *src.RangeStart
+		}
+		end := int64(0)
+		if src.RangeEnd != nil {
+			end = *src.RangeEnd
+		} else {
+			headInput := &s3.HeadObjectInput{Bucket: aws.String(bucket), Key: aws.String(src.Key)}
+			if src.SSECustomerAlgorithm != "" {
+				headInput.SSECustomerAlgorithm = aws.String(src.SSECustomerAlgorithm)
+			}
+			if src.SSECustomerKey != "" {
+				headInput.SSECustomerKey = aws.String(src.SSECustomerKey)
+			}
+			if src.SSECustomerKeyMD5 != "" {
+				headInput.SSECustomerKeyMD5 = aws.String(src.SSECustomerKeyMD5)
+			}
+			head, err := client.HeadObject(ctx, headInput)
+			if err != nil {
+				return nil, fmt.Errorf("stat source %v:%v: %w", bucket, src.Key, err)
+			}
+			if head.ContentLength != nil {
+				end = *head.ContentLength - 1
+			}
+		}
+
+		for chunkStart := start; chunkStart <= end; chunkStart += maxCopyPartSize {
+			chunkEnd := chunkStart + maxCopyPartSize - 1
+			if chunkEnd > end {
+				chunkEnd = end
+			}
+			parts = append(parts, copyPart{
+				bucket: bucket, key: src.Key, start: chunkStart, end: chunkEnd,
+				sseCustomerAlgorithm: src.SSECustomerAlgorithm, sseCustomerKey: src.SSECustomerKey,
+				sseCustomerKeyMD5: src.SSECustomerKeyMD5,
+			})
+		}
+	}
+	return parts, nil
+}
+
+// validateCopyPartSizes enforces S3's multipart rule that every part except
+// the last must be at least minCopyPartSize. Composing several small
+// sources (a log-rollup merge, for example) would otherwise produce
+// undersized parts that fail confusingly inside CompleteMultipartUpload with
+// EntityTooSmall instead of a clear, up-front error.
+func validateCopyPartSizes(parts []copyPart) error {
+	for i, part := range parts {
+		if i == len(parts)-1 {
+			continue // the last part may be smaller
+		}
+		if part.size() < minCopyPartSize {
+			return fmt.Errorf("source %v:%v range [%d-%d] is %d bytes, below the %d byte minimum S3 requires for all but the last part of a composed object",
+				part.bucket, part.key, part.start, part.end, part.size(), minCopyPartSize)
+		}
+	}
+	return nil
+}
+
+// encodeCopySource builds the CopySource header value S3 expects: bucket and
+// key URL-encoded per path segment, so keys containing spaces, '+', or other
+// reserved characters copy correctly. The '/' separators are preserved
+// literally rather than encoded, since they delimit the key's path segments.
+func encodeCopySource(bucket, key string) string {
+	segments := strings.Split(key, "/")
+	for i, seg := range segments {
+		segments[i] = url.PathEscape(seg)
+	}
+	return fmt.Sprintf("%s/%s", url.PathEscape(bucket), strings.Join(segments, "/"))
+}
+
+// composeS3 copies each source into destBucket/destKey. A single
+// whole-object source uses CopyObject; everything else (byte ranges,
+// multiple sources, or sources over maxCopyPartSize) goes through a
+// multipart upload built from UploadPartCopy calls.
+func (c *Composer) composeS3(ctx context.Context, destBucket, destKey string, sources []composeSource) (string, error) {
+	parts, err := planCopyParts(ctx, c.S3Client, destBucket, sources)
+	if err != nil {
+		return "", err
+	}
+	if len(sources) == 1 && sources[0].RangeStart == nil && sources[0].RangeEnd == nil && len(parts) <= 1 {
+		return c.copyWholeObject(ctx, destBucket, destKey, sources[0])
+	}
+	if err := validateCopyPartSizes(parts); err != nil {
+		return "", err
+	}
+
+	created, err := c.S3Client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket: aws.String(destBucket),
+		Key:    aws.String(destKey),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating multipart upload for %v:%v: %w", destBucket, destKey, err)
+	}
+	uploadID := created.UploadId
+
+	abort := func(cause error) (string, error) {
+		c.S3Client.AbortMultipartUpload(ctx, &s3.AbortMultipartUploadInput{
+			Bucket: aws.String(destBucket), Key: aws.String(destKey), UploadId: uploadID,
+		})
+		return "", cause
+	}
+
+	var completedParts []types.CompletedPart
+	for i, part := range parts {
+		partNumber := int32(i + 1)
+
+		input := &s3.UploadPartCopyInput{
+			Bucket:          aws.String(destBucket),
+			Key:             aws.String(destKey),
+			UploadId:        uploadID,
+			PartNumber:      aws.Int32(partNumber),
+			CopySource:      aws.String(encodeCopySource(part.bucket, part.key)),
+			CopySourceRange: aws.String(fmt.Sprintf("bytes=%d-%d", part.start, part.end)),
+		}
+		// These decrypt an SSE-C source object for the copy, so they belong on
+		// the CopySourceSSE* fields; SSECustomerAlgorithm/Key would instead
+		// (re-)encrypt the destination part, which isn't what a source-side
+		// sseCustomerKey is for.
+		if part.sseCustomerAlgorithm != "" {
+			input.CopySourceSSECustomerAlgorithm = aws.String(part.sseCustomerAlgorithm)
+		}
+		if part.sseCustomerKey != "" {
+			input.CopySourceSSECustomerKey = aws.String(part.sseCustomerKey)
+		}
+		if part.sseCustomerKeyMD5 != "" {
+			input.CopySourceSSECustomerKeyMD5 = aws.String(part.sseCustomerKeyMD5)
+		}
+
+		result, err := c.S3Client.UploadPartCopy(ctx, input)
+		if err != nil {
+			return abort(fmt.Errorf("copying %v:%v [%d-%d]: %w", part.bucket, part.key, part.start, part.end, err))
+		}
+
+		completedParts = append(completedParts, types.CompletedPart{
+			ETag:       result.CopyPartResult.ETag,
+			PartNumber: aws.Int32(partNumber),
+		})
+	}
+
+	completed, err := c.S3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(destBucket),
+		Key:             aws.String(destKey),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return abort(fmt.Errorf("completing multipart upload for %v:%v: %w", destBucket, destKey, err))
+	}
+
+	if completed.ETag != nil {
+		return *completed.ETag, nil
+	}
+	return "", nil
+}
+
+func (c *Composer) copyWholeObject(ctx context.Context, destBucket, destKey string, src composeSource) (string, error) {
+	bucket := src.Bucket
+	if bucket == "" {
+		bucket = destBucket
+	}
+	input := &s3.CopyObjectInput{
+		Bucket:     aws.String(destBucket),
+		Key:        aws.String(destKey),
+		CopySource: aws.String(encodeCopySource(bucket, src.Key)),
+	}
+	if src.SSECustomerAlgorithm != "" {
+		input.CopySourceSSECustomerAlgorithm = aws.String(src.SSECustomerAlgorithm)
+	}
+	if src.SSECustomerKey != "" {
+		input.CopySourceSSECustomerKey = aws.String(src.SSECustomerKey)
+	}
+	if src.SSECustomerKeyMD5 != "" {
+		input.CopySourceSSECustomerKeyMD5 = aws.String(src.SSECustomerKeyMD5)
+	}
+	output, err := c.S3Client.CopyObject(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("copying %v:%v to %v:%v: %w", bucket, src.Key, destBucket, destKey, err)
+	}
+	if output.CopyObjectResult != nil && output.CopyObjectResult.ETag != nil {
+		return *output.CopyObjectResult.ETag, nil
+	}
+	return "", nil
+}
+
+// handleCompose serves POST /compose/{bucket}/{key}, assembling the
+// destination object from the JSON-listed sources without streaming any of
+// their bytes through the proxy.
+func handleCompose(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/compose/")
+	destBucket, destKey, ok := extractBucketAndKey(path)
+	if !ok {
+		http.Error(w, "expected /compose/{bucket}/{key}", http.StatusBadRequest)
+		return
+	}
+
+	var req composeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing compose request: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	composer, err := NewComposer()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating composer: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	etag, err := composer.Compose(r.Context(), destBucket, destKey, req.Sources)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error composing object: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(composeResponse{ETag: etag})
+}
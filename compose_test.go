@@ -0,0 +1,38 @@
+package main
+
+import "testing"
+
+func TestValidateCopyPartSizesRejectsUndersizedNonFinalPart(t *testing.T) {
+	parts := []copyPart{
+		{bucket: "b", key: "small-1", start: 0, end: 1024}, // 1025 bytes, not the last part
+		{bucket: "b", key: "small-2", start: 0, end: 1024},
+	}
+	if err := validateCopyPartSizes(parts); err == nil {
+		t.Fatal("expected an error for an undersized non-final part")
+	}
+}
+
+func TestValidateCopyPartSizesAllowsUndersizedFinalPart(t *testing.T) {
+	parts := []copyPart{
+		{bucket: "b", key: "big", start: 0, end: minCopyPartSize - 1}, // exactly the minimum
+		{bucket: "b", key: "small", start: 0, end: 1024},              // last part, can be small
+	}
+	if err := validateCopyPartSizes(parts); err != nil {
+		t.Errorf("unexpected error when only the final part is small: %v", err)
+	}
+}
+
+func TestValidateCopyPartSizesSingleSmallPartIsFine(t *testing.T) {
+	parts := []copyPart{{bucket: "b", key: "only", start: 0, end: 99}}
+	if err := validateCopyPartSizes(parts); err != nil {
+		t.Errorf("unexpected error for a single (necessarily last) small part: %v", err)
+	}
+}
+
+func TestEncodeCopySourceEscapesSpacesButKeepsSlashes(t *testing.T) {
+	got := encodeCopySource("my bucket", "dir with spaces/file name.txt")
+	want := "my%20bucket/dir%20with%20spaces/file%20name.txt"
+	if got != want {
+		t.Errorf("encodeCopySource = %q, want %q", got, want)
+	}
+}
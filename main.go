@@ -1,24 +1,20 @@
 package main
 
 import (
-	"bytes"
 	"context"
-	"errors"
+	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"net/url"
 	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
-	awsConfig "github.com/aws/aws-sdk-go-v2/config"
-	awsCredentials "github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/joho/godotenv"
-	"github.com/minio/minio-go/v7"
-	minioCredentials "github.com/minio/minio-go/v7/pkg/credentials"
 	"github.com/spf13/viper"
 )
 
@@ -26,12 +22,20 @@ type ObjectStorageConfig struct {
 	AccessKey string
 	SecretKey string
 	Endpoint  string
+	// Region is only meaningful for AWSConfig; MinioConfig's backends take
+	// "auto" regardless (see legacyBackendConfig).
+	Region string
 }
 
 type Config struct {
 	MinioConfig ObjectStorageConfig
 	AWSConfig   ObjectStorageConfig
 	ServiceName string
+	// Backends lists the gateways routable by the {backend} path segment
+	// (see objectstore.go). It is in addition to, not a replacement for,
+	// MinioConfig/AWSConfig/ServiceName, which still back the legacy
+	// /stream and /upload routes.
+	Backends []BackendConfig
 }
 
 var globalConfig Config
@@ -52,10 +56,17 @@ func loadConfig(path string) error {
 			AccessKey: conf.GetString("AWS_ACCESS_KEY"),
 			SecretKey: conf.GetString("AWS_SECRET_KEY"),
 			Endpoint:  conf.GetString("AWS_ENDPOINT"),
+			Region:    conf.GetString("AWS_REGION"),
 		},
 		ServiceName: conf.GetString("SERVICE_NAME"),
 	}
 
+	if backendsJSON := conf.GetString("BACKENDS_CONFIG"); backendsJSON != "" {
+		if err := json.Unmarshal([]byte(backendsJSON), &globalConfig.Backends); err != nil {
+			return fmt.Errorf("parsing BACKENDS_CONFIG: %w", err)
+		}
+	}
+
 	return nil
 }
 
@@ -63,12 +74,62 @@ type Presigner struct {
 	PresignClient *s3.PresignClient
 }
 
-func (presigner Presigner) GetObject(bucketName string, objectKey string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error) {
+// responseOverrideQueryParams lists the S3 GET response-header override query
+// parameters. Each name is also the minio-go PresignedGetObject reqParams key
+// (the same name the S3 API uses on the wire); Presigner.GetObject sets the
+// matching AWS SDK GetObjectInput field for each.
+var responseOverrideQueryParams = []string{
+	"response-content-type",
+	"response-content-disposition",
+	"response-cache-control",
+	"response-expires",
+	"response-content-encoding",
+	"response-content-language",
+}
+
+// extractResponseOverrides pulls the S3 response-header override query
+// parameters off the incoming request so they can be threaded through to the
+// presign call unchanged.
+func extractResponseOverrides(query url.Values) map[string]string {
+	overrides := make(map[string]string)
+	for _, param := range responseOverrideQueryParams {
+		if value := query.Get(param); value != "" {
+			overrides[param] = value
+		}
+	}
+	return overrides
+}
+
+func (presigner Presigner) GetObject(bucketName string, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (*v4.PresignedHTTPRequest, error) {
 	fmt.Println("Getting a presigned request to get object:", bucketName, objectKey)
-	request, err := presigner.PresignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+	input := &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
-	}, func(opts *s3.PresignOptions) {
+	}
+	if v := responseOverrides["response-content-type"]; v != "" {
+		input.ResponseContentType = aws.String(v)
+	}
+	if v := responseOverrides["response-content-disposition"]; v != "" {
+		input.ResponseContentDisposition = aws.String(v)
+	}
+	if v := responseOverrides["response-cache-control"]; v != "" {
+		input.ResponseCacheControl = aws.String(v)
+	}
+	if v := responseOverrides["response-expires"]; v != "" {
+		if expires, err := http.ParseTime(v); err == nil {
+			input.ResponseExpires = aws.Time(expires)
+		} else {
+			log.Printf("Ignoring unparseable response-expires override %q: %v\n", v, err)
+		}
+	}
+	if v := responseOverrides["response-content-encoding"]; v != "" {
+		input.ResponseContentEncoding = aws.String(v)
+	}
+	if v := responseOverrides["response-content-language"]; v != "" {
+		input.ResponseContentLanguage = aws.String(v)
+	}
+
+	request, err := presigner.PresignClient.PresignGetObject(context.TODO(), input, func(opts *s3.PresignOptions) {
 		opts.Expires = time.Duration(lifetimeSecs * int64(time.Second))
 	})
 	if err != nil {
@@ -78,54 +139,36 @@ func (presigner Presigner) GetObject(bucketName string, objectKey string, lifeti
 	return request, err
 }
 
-func getPresignedURL(bucketName, fileName string, lifetimeSecs int64) (string, error) {
-	if globalConfig.ServiceName == "minio" {
-		// Initialize Minio client
-		minioClient, err := minio.New(globalConfig.MinioConfig.Endpoint, &minio.Options{
-			Creds:  minioCredentials.NewStaticV4(globalConfig.MinioConfig.AccessKey, globalConfig.MinioConfig.SecretKey, ""),
-			Secure: true,
-			Region: "auto",
-		})
-		if err != nil {
-			return "", err
-		}
-
-		// Generates a presigned url which expires in a day.
-		respURL, err := minioClient.PresignedGetObject(context.Background(), bucketName, fileName, time.Second*60, nil)
-		if err != nil {
-			return "", err
-		}
-
-		return respURL.String(), nil
-	} else if globalConfig.ServiceName == "S3" || globalConfig.ServiceName == "R2" {
-		resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
-			return aws.Endpoint{
-				URL: globalConfig.AWSConfig.Endpoint,
-			}, nil
-		})
-
-		awsCfg, err := awsConfig.LoadDefaultConfig(context.TODO(),
-			awsConfig.WithEndpointResolverWithOptions(resolver),
-			awsConfig.WithCredentialsProvider(awsCredentials.NewStaticCredentialsProvider(globalConfig.AWSConfig.AccessKey, globalConfig.AWSConfig.SecretKey, "")),
-			awsConfig.WithRegion("auto"),
-		)
-		if err != nil {
-			return "", err
-		}
-
-		s3Client := s3.NewFromConfig(awsCfg)
-		presignClient := s3.NewPresignClient(s3Client)
-		presigner := Presigner{PresignClient: presignClient}
-
-		respURL, err := presigner.GetObject(bucketName, fileName, lifetimeSecs)
-		if err != nil {
-			return "", err
-		}
-
-		return respURL.URL, nil
+// getPresignedURL keeps serving the single-backend /stream route by
+// presigning against whichever backend globalConfig.ServiceName
+// names, now delegating to the same ObjectStore implementations the
+// {backend}-routed endpoints use.
+func getPresignedURL(bucketName, fileName string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	store, err := newObjectStore(legacyBackendConfig())
+	if err != nil {
+		return "", err
 	}
+	return store.PresignGet(bucketName, fileName, lifetimeSecs, responseOverrides)
+}
 
-	return "", errors.New("unsupported object storage service")
+// passThroughRequestHeaders are the client headers forwarded to the upstream
+// presigned request so range/conditional semantics behave like a real S3 client.
+var passThroughRequestHeaders = []string{
+	"Range",
+	"If-Modified-Since",
+	"If-None-Match",
+	"If-Match",
+}
+
+// passThroughResponseHeaders are the upstream response headers relayed back to
+// the client untouched.
+var passThroughResponseHeaders = []string{
+	"Content-Length",
+	"Content-Range",
+	"Content-Type",
+	"ETag",
+	"Last-Modified",
+	"Accept-Ranges",
 }
 
 func handleRequest(w http.ResponseWriter, r *http.Request) {
@@ -141,7 +184,7 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("bucketName:::::", bucketName)
 	fmt.Println("fileName:::::", fileName)
 
-	presignedURL, err := getPresignedURL(bucketName, fileName, 10)
+	presignedURL, err := getPresignedURL(bucketName, fileName, 10, extractResponseOverrides(r.URL.Query()))
 	if err != nil {
 		fmt.Fprintf(w, "Error getting presigned URL: %v", err)
 		return
@@ -151,28 +194,41 @@ func handleRequest(w http.ResponseWriter, r *http.Request) {
 		presignedURL)
 	log.Println("Using net/http to send the request...")
 
-	getResponse, err := http.Get(presignedURL)
+	upstreamReq, err := http.NewRequest(http.MethodGet, presignedURL, nil)
 	if err != nil {
-		fmt.Fprintf(w, "Error getting object: %v", err)
+		fmt.Fprintf(w, "Error building upstream request: %v", err)
 		return
 	}
+	for _, header := range passThroughRequestHeaders {
+		if value := r.Header.Get(header); value != "" {
+			upstreamReq.Header.Set(header, value)
+		}
+	}
 
-	defer getResponse.Body.Close()
-
-	buffer := bytes.NewBuffer(nil)
-	_, err = io.Copy(buffer, getResponse.Body)
+	getResponse, err := http.DefaultClient.Do(upstreamReq)
 	if err != nil {
-		fmt.Fprintf(w, "Error reading object: %v", err)
+		fmt.Fprintf(w, "Error getting object: %v", err)
 		return
 	}
+	defer getResponse.Body.Close()
 
-	// Set the Content-Type header explicitly
-	w.Header().Set("Content-Type", "application/octet-stream")
-	w.Header().Set("Accept-Ranges", "bytes")
-	w.Header().Set("Content-Length", fmt.Sprintf("%v", buffer.Len()))
+	for _, header := range passThroughResponseHeaders {
+		if value := getResponse.Header.Get(header); value != "" {
+			w.Header().Set(header, value)
+		}
+	}
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "application/octet-stream")
+	}
+	if w.Header().Get("Accept-Ranges") == "" {
+		w.Header().Set("Accept-Ranges", "bytes")
+	}
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 
-	http.ServeContent(w, r, fileName, time.Time{}, bytes.NewReader(buffer.Bytes()))
+	w.WriteHeader(getResponse.StatusCode)
+	if _, err := io.Copy(w, getResponse.Body); err != nil {
+		log.Printf("Error streaming object %v:%v to client: %v\n", bucketName, fileName, err)
+	}
 }
 
 func extractBucketName(path string) string {
@@ -191,6 +247,18 @@ func extractFileName(path string) string {
 	return strings.Join(segments[2:], "/")
 }
 
+// extractBucketAndKey splits a /{route}/{bucket}/{key...} path (with the
+// route's mount prefix already trimmed) into its bucket and key segments,
+// reporting ok=false instead of panicking like extractBucketName/
+// extractFileName do when the path has no key segment.
+func extractBucketAndKey(path string) (bucketName, objectKey string, ok bool) {
+	segments := strings.SplitN(path, "/", 2)
+	if len(segments) < 2 || segments[0] == "" || segments[1] == "" {
+		return "", "", false
+	}
+	return segments[0], segments[1], true
+}
+
 func main() {
 	// Load configuration
 	if err := loadConfig("."); err != nil {
@@ -199,6 +267,10 @@ func main() {
 
 	// Start server
 	http.HandleFunc("/stream/", handleRequest)
+	http.HandleFunc("/upload/", handleUpload)
+	http.HandleFunc("/object/", handleObjectStore)
+	http.HandleFunc("/select/", handleSelect)
+	http.HandleFunc("/compose/", handleCompose)
 	fmt.Println("Listening on :8000")
 	log.Fatal(http.ListenAndServe(":8080", nil))
 }
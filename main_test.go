@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestExtractBucketAndKey(t *testing.T) {
+	tests := []struct {
+		path       string
+		wantBucket string
+		wantKey    string
+		wantOK     bool
+	}{
+		{"bucket/key", "bucket", "key", true},
+		{"bucket/dir/file.txt", "bucket", "dir/file.txt", true},
+		{"onlybucket", "", "", false},
+		{"", "", "", false},
+		{"bucket/", "", "", false},
+	}
+
+	for _, tt := range tests {
+		bucket, key, ok := extractBucketAndKey(tt.path)
+		if ok != tt.wantOK || bucket != tt.wantBucket || key != tt.wantKey {
+			t.Errorf("extractBucketAndKey(%q) = (%q, %q, %v), want (%q, %q, %v)",
+				tt.path, bucket, key, ok, tt.wantBucket, tt.wantKey, tt.wantOK)
+		}
+	}
+}
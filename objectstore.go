@@ -0,0 +1,1058 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+	"google.golang.org/api/option"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/sas"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsConfig "github.com/aws/aws-sdk-go-v2/config"
+	awsCredentials "github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/feature/s3/manager"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/minio/minio-go/v7"
+	minioCredentials "github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// BackendConfig describes one object storage backend the proxy can front.
+// Name is the {backend} path segment clients use to address it.
+type BackendConfig struct {
+	Name        string
+	ServiceName string // minio, S3, R2, GCS, Azure, FS
+	Endpoint    string
+	Region      string
+	AccessKey   string
+	SecretKey   string
+}
+
+// ObjectInfo is the backend-agnostic metadata returned by Stat/List/Get.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ETag         string
+	ContentType  string
+	LastModified time.Time
+}
+
+// ObjectStore is implemented by every backend the proxy can gateway to, so
+// handlers can be written once against bucket/key instead of branching on
+// globalConfig.ServiceName.
+type ObjectStore interface {
+	PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error)
+	PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error)
+	GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error)
+	PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error)
+	StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error)
+	ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error)
+	DeleteObject(ctx context.Context, bucketName, objectKey string) error
+}
+
+// newObjectStore builds the ObjectStore for a single backend config.
+func newObjectStore(cfg BackendConfig) (ObjectStore, error) {
+	switch strings.ToLower(cfg.ServiceName) {
+	case "minio":
+		return &minioObjectStore{cfg: cfg}, nil
+	case "s3", "r2":
+		return &s3ObjectStore{cfg: cfg}, nil
+	case "gcs":
+		return &gcsObjectStore{cfg: cfg}, nil
+	case "azure":
+		return &azureObjectStore{cfg: cfg}, nil
+	case "fs", "filesystem":
+		return &fsObjectStore{cfg: cfg}, nil
+	}
+	return nil, fmt.Errorf("unsupported backend service %q", cfg.ServiceName)
+}
+
+// getBackend resolves the {backend} path segment to a configured ObjectStore.
+func getBackend(name string) (ObjectStore, error) {
+	for _, cfg := range globalConfig.Backends {
+		if cfg.Name == name {
+			return newObjectStore(cfg)
+		}
+	}
+	return nil, fmt.Errorf("unknown backend %q", name)
+}
+
+// legacyBackendConfig maps the pre-existing single-backend MinioConfig/
+// AWSConfig/ServiceName fields onto a BackendConfig, so getPresignedURL and
+// Uploader keep working unconfigured via the `backends` list.
+func legacyBackendConfig() BackendConfig {
+	if strings.EqualFold(globalConfig.ServiceName, "minio") {
+		return BackendConfig{
+			Name:        "default",
+			ServiceName: "minio",
+			Endpoint:    globalConfig.MinioConfig.Endpoint,
+			AccessKey:   globalConfig.MinioConfig.AccessKey,
+			SecretKey:   globalConfig.MinioConfig.SecretKey,
+		}
+	}
+	return BackendConfig{
+		Name:        "default",
+		ServiceName: globalConfig.ServiceName,
+		Endpoint:    globalConfig.AWSConfig.Endpoint,
+		Region:      globalConfig.AWSConfig.Region,
+		AccessKey:   globalConfig.AWSConfig.AccessKey,
+		SecretKey:   globalConfig.AWSConfig.SecretKey,
+	}
+}
+
+// --- minio -----------------------------------------------------------------
+
+type minioObjectStore struct {
+	cfg BackendConfig
+}
+
+// newMinioClient builds the *minio.Client shared by every MinIO-backed code
+// path (minioObjectStore, and the legacy Uploader/Composer), mirroring
+// newS3Client below.
+func newMinioClient(cfg BackendConfig) (*minio.Client, error) {
+	return minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  minioCredentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, ""),
+		Secure: true,
+		Region: "auto",
+	})
+}
+
+func (s *minioObjectStore) client() (*minio.Client, error) {
+	return newMinioClient(s.cfg)
+}
+
+func (s *minioObjectStore) PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	reqParams := extractResponseOverridesValues(responseOverrides)
+	respURL, err := client.PresignedGetObject(context.Background(), bucketName, objectKey, time.Duration(lifetimeSecs)*time.Second, reqParams)
+	if err != nil {
+		return "", err
+	}
+	return respURL.String(), nil
+}
+
+func (s *minioObjectStore) PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	respURL, err := client.PresignedPutObject(context.Background(), bucketName, objectKey, time.Duration(lifetimeSecs)*time.Second)
+	if err != nil {
+		return "", err
+	}
+	return respURL.String(), nil
+}
+
+func (s *minioObjectStore) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	obj, err := client.GetObject(ctx, bucketName, objectKey, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, ObjectInfo{}, err
+	}
+	return obj, minioStatToInfo(stat), nil
+}
+
+func (s *minioObjectStore) PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	info, err := client.PutObject(ctx, bucketName, objectKey, body, size, minio.PutObjectOptions{
+		ContentType:  opts.ContentType,
+		UserMetadata: opts.Metadata,
+		PartSize:     defaultPartSize,
+		NumThreads:   defaultUploadConcurrency,
+	})
+	if err != nil {
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+func (s *minioObjectStore) StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	stat, err := client.StatObject(ctx, bucketName, objectKey, minio.StatObjectOptions{})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return minioStatToInfo(stat), nil
+}
+
+func (s *minioObjectStore) ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	var infos []ObjectInfo
+	for obj := range client.ListObjects(ctx, bucketName, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if obj.Err != nil {
+			return nil, obj.Err
+		}
+		infos = append(infos, ObjectInfo{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			ETag:         obj.ETag,
+			ContentType:  obj.ContentType,
+			LastModified: obj.LastModified,
+		})
+	}
+	return infos, nil
+}
+
+func (s *minioObjectStore) DeleteObject(ctx context.Context, bucketName, objectKey string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	return client.RemoveObject(ctx, bucketName, objectKey, minio.RemoveObjectOptions{})
+}
+
+func minioStatToInfo(stat minio.ObjectInfo) ObjectInfo {
+	return ObjectInfo{
+		Key:          stat.Key,
+		Size:         stat.Size,
+		ETag:         stat.ETag,
+		ContentType:  stat.ContentType,
+		LastModified: stat.LastModified,
+	}
+}
+
+func extractResponseOverridesValues(responseOverrides map[string]string) url.Values {
+	values := make(url.Values)
+	for k, v := range responseOverrides {
+		values.Set(k, v)
+	}
+	return values
+}
+
+// --- S3 / R2 -----------------------------------------------------------------
+
+type s3ObjectStore struct {
+	cfg BackendConfig
+}
+
+// newS3Client builds the *s3.Client shared by every S3/R2-backed code path
+// (s3ObjectStore, the legacy Uploader/Composer, and the /select S3 Select
+// path), so the endpoint/credential/region wiring is defined exactly once.
+// cfg.Region is honored when set; it defaults to "auto", which is the R2
+// convention and works against R2-compatible endpoints, but should be set
+// explicitly to a real AWS region (e.g. "us-east-1") when ServiceName is
+// "S3", since SigV4 signs against the region it's given.
+func newS3Client(cfg BackendConfig) (*s3.Client, error) {
+	region := cfg.Region
+	if region == "" {
+		region = "auto"
+	}
+	resolver := aws.EndpointResolverWithOptionsFunc(func(service, region string, options ...interface{}) (aws.Endpoint, error) {
+		return aws.Endpoint{URL: cfg.Endpoint}, nil
+	})
+
+	awsCfg, err := awsConfig.LoadDefaultConfig(context.TODO(),
+		awsConfig.WithEndpointResolverWithOptions(resolver),
+		awsConfig.WithCredentialsProvider(awsCredentials.NewStaticCredentialsProvider(cfg.AccessKey, cfg.SecretKey, "")),
+		awsConfig.WithRegion(region),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(awsCfg), nil
+}
+
+func (s *s3ObjectStore) client() (*s3.Client, error) {
+	return newS3Client(s.cfg)
+}
+
+func (s *s3ObjectStore) PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	presigner := Presigner{PresignClient: s3.NewPresignClient(client)}
+	request, err := presigner.GetObject(bucketName, objectKey, lifetimeSecs, responseOverrides)
+	if err != nil {
+		return "", err
+	}
+	return request.URL, nil
+}
+
+func (s *s3ObjectStore) PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	presignClient := s3.NewPresignClient(client)
+	request, err := presignClient.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = time.Duration(lifetimeSecs) * time.Second
+	})
+	if err != nil {
+		return "", err
+	}
+	return request.URL, nil
+}
+
+func (s *s3ObjectStore) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	output, err := client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	info := ObjectInfo{Key: objectKey}
+	if output.ContentLength != nil {
+		info.Size = *output.ContentLength
+	}
+	if output.ETag != nil {
+		info.ETag = *output.ETag
+	}
+	if output.ContentType != nil {
+		info.ContentType = *output.ContentType
+	}
+	if output.LastModified != nil {
+		info.LastModified = *output.LastModified
+	}
+	return output.Body, info, nil
+}
+
+func (s *s3ObjectStore) PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	etag, _, err := putObjectS3(ctx, client, bucketName, objectKey, body, size, opts, defaultMultipartThreshold)
+	return etag, err
+}
+
+// putObjectS3 uploads body to bucketName/objectKey via client, switching to
+// the resumable putObjectS3Multipart once the object crosses
+// multipartThreshold or the caller supplies opts.UploadID to resume. Shared
+// by s3ObjectStore.PutObject and the legacy Uploader so opts.UploadID and
+// opts.SSEHeaders are honored identically whether a client comes in through
+// /object or /upload.
+func putObjectS3(ctx context.Context, client *s3.Client, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions, multipartThreshold int64) (etag, uploadID string, err error) {
+	if opts.UploadID != "" || size < 0 || size > multipartThreshold {
+		return putObjectS3Multipart(ctx, client, bucketName, objectKey, body, opts)
+	}
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+		Body:   body,
+	}
+	if opts.ContentType != "" {
+		input.ContentType = aws.String(opts.ContentType)
+	}
+	if len(opts.Metadata) > 0 {
+		input.Metadata = opts.Metadata
+	}
+	if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption"]; v != "" {
+		input.ServerSideEncryption = types.ServerSideEncryption(v)
+	}
+	if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"]; v != "" {
+		input.SSEKMSKeyId = aws.String(v)
+	}
+	if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Algorithm"]; v != "" {
+		input.SSECustomerAlgorithm = aws.String(v)
+	}
+	if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key"]; v != "" {
+		input.SSECustomerKey = aws.String(v)
+	}
+	if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key-Md5"]; v != "" {
+		input.SSECustomerKeyMD5 = aws.String(v)
+	}
+
+	uploader := manager.NewUploader(client, func(u *manager.Uploader) {
+		u.PartSize = defaultPartSize
+		u.Concurrency = defaultUploadConcurrency
+	})
+	output, err := uploader.Upload(ctx, input)
+	if err != nil {
+		return "", "", fmt.Errorf("S3 upload %v:%v: %w", bucketName, objectKey, err)
+	}
+	if output.ETag != nil {
+		return *output.ETag, "", nil
+	}
+	return "", "", nil
+}
+
+// putObjectS3Multipart uploads body to bucketName/objectKey part by part
+// through CreateMultipartUpload/UploadPart/CompleteMultipartUpload, so a
+// failed part can be retried without re-uploading the whole object: the
+// client resends the same request with an Upload-Id header set to the one
+// returned on the failed attempt, and ListParts tells us which parts already
+// landed so we only upload what's left.
+func putObjectS3Multipart(ctx context.Context, client *s3.Client, bucketName, objectKey string, body io.Reader, opts uploadOptions) (etag, uploadID string, err error) {
+	uploadID = opts.UploadID
+	var completedParts []types.CompletedPart
+
+	if uploadID == "" {
+		input := &s3.CreateMultipartUploadInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		}
+		if opts.ContentType != "" {
+			input.ContentType = aws.String(opts.ContentType)
+		}
+		if len(opts.Metadata) > 0 {
+			input.Metadata = opts.Metadata
+		}
+		if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption"]; v != "" {
+			input.ServerSideEncryption = types.ServerSideEncryption(v)
+		}
+		if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id"]; v != "" {
+			input.SSEKMSKeyId = aws.String(v)
+		}
+		if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Algorithm"]; v != "" {
+			input.SSECustomerAlgorithm = aws.String(v)
+		}
+		if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key"]; v != "" {
+			input.SSECustomerKey = aws.String(v)
+		}
+		if v := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key-Md5"]; v != "" {
+			input.SSECustomerKeyMD5 = aws.String(v)
+		}
+		created, err := client.CreateMultipartUpload(ctx, input)
+		if err != nil {
+			return "", "", fmt.Errorf("creating multipart upload for %v:%v: %w", bucketName, objectKey, err)
+		}
+		uploadID = *created.UploadId
+	} else {
+		var partNumberMarker *string
+		for {
+			listed, err := client.ListParts(ctx, &s3.ListPartsInput{
+				Bucket:           aws.String(bucketName),
+				Key:              aws.String(objectKey),
+				UploadId:         aws.String(uploadID),
+				PartNumberMarker: partNumberMarker,
+			})
+			if err != nil {
+				return "", uploadID, fmt.Errorf("resuming upload %v:%v (upload-id %v): listing completed parts: %w", bucketName, objectKey, uploadID, err)
+			}
+			for _, part := range listed.Parts {
+				completedParts = append(completedParts, types.CompletedPart{ETag: part.ETag, PartNumber: part.PartNumber})
+				if part.Size != nil {
+					if _, err := io.CopyN(io.Discard, body, *part.Size); err != nil {
+						return "", uploadID, fmt.Errorf("resuming upload %v:%v (upload-id %v): skipping already-uploaded part %d: %w", bucketName, objectKey, uploadID, *part.PartNumber, err)
+					}
+				}
+			}
+			if listed.IsTruncated == nil || !*listed.IsTruncated {
+				break
+			}
+			partNumberMarker = listed.NextPartNumberMarker
+		}
+	}
+
+	// SSE-C requires the same customer key on every UploadPart call, not just
+	// at CreateMultipartUpload; SSE-S3/KMS, by contrast, is fixed for the
+	// whole object at CreateMultipartUpload time and UploadPartInput has no
+	// field for it.
+	sseCAlgorithm := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Algorithm"]
+	sseCKey := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key"]
+	sseCKeyMD5 := opts.SSEHeaders["X-Amz-Server-Side-Encryption-Customer-Key-Md5"]
+
+	partNumber := int32(len(completedParts) + 1)
+	buf := make([]byte, defaultPartSize)
+	for {
+		n, readErr := io.ReadFull(body, buf)
+		if n > 0 {
+			partInput := &s3.UploadPartInput{
+				Bucket:     aws.String(bucketName),
+				Key:        aws.String(objectKey),
+				UploadId:   aws.String(uploadID),
+				PartNumber: aws.Int32(partNumber),
+				Body:       bytes.NewReader(buf[:n]),
+			}
+			if sseCAlgorithm != "" {
+				partInput.SSECustomerAlgorithm = aws.String(sseCAlgorithm)
+			}
+			if sseCKey != "" {
+				partInput.SSECustomerKey = aws.String(sseCKey)
+			}
+			if sseCKeyMD5 != "" {
+				partInput.SSECustomerKeyMD5 = aws.String(sseCKeyMD5)
+			}
+			result, err := client.UploadPart(ctx, partInput)
+			if err != nil {
+				return "", uploadID, fmt.Errorf("uploading part %d of %v:%v (upload-id %v, retry with the same Upload-Id header to resume): %w", partNumber, bucketName, objectKey, uploadID, err)
+			}
+			completedParts = append(completedParts, types.CompletedPart{ETag: result.ETag, PartNumber: aws.Int32(partNumber)})
+			partNumber++
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			return "", uploadID, fmt.Errorf("reading body for %v:%v (upload-id %v, retry with the same Upload-Id header to resume): %w", bucketName, objectKey, uploadID, readErr)
+		}
+	}
+
+	completed, err := client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(objectKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return "", uploadID, fmt.Errorf("completing multipart upload for %v:%v (upload-id %v, retry with the same Upload-Id header to resume): %w", bucketName, objectKey, uploadID, err)
+	}
+	if completed.ETag != nil {
+		return *completed.ETag, uploadID, nil
+	}
+	return "", uploadID, nil
+}
+
+func (s *s3ObjectStore) StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	output, err := client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info := ObjectInfo{Key: objectKey}
+	if output.ContentLength != nil {
+		info.Size = *output.ContentLength
+	}
+	if output.ETag != nil {
+		info.ETag = *output.ETag
+	}
+	if output.LastModified != nil {
+		info.LastModified = *output.LastModified
+	}
+	return info, nil
+}
+
+func (s *s3ObjectStore) ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	output, err := client.ListObjectsV2(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	})
+	if err != nil {
+		return nil, err
+	}
+	infos := make([]ObjectInfo, 0, len(output.Contents))
+	for _, obj := range output.Contents {
+		info := ObjectInfo{}
+		if obj.Key != nil {
+			info.Key = *obj.Key
+		}
+		if obj.Size != nil {
+			info.Size = *obj.Size
+		}
+		if obj.ETag != nil {
+			info.ETag = *obj.ETag
+		}
+		if obj.LastModified != nil {
+			info.LastModified = *obj.LastModified
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+func (s *s3ObjectStore) DeleteObject(ctx context.Context, bucketName, objectKey string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	return err
+}
+
+// --- Google Cloud Storage ----------------------------------------------------
+
+type gcsObjectStore struct {
+	cfg BackendConfig
+}
+
+func (s *gcsObjectStore) client(ctx context.Context) (*storage.Client, error) {
+	var opts []option.ClientOption
+	if s.cfg.AccessKey != "" {
+		// AccessKey holds the path to a service-account JSON key file.
+		opts = append(opts, option.WithCredentialsFile(s.cfg.AccessKey))
+	}
+	if s.cfg.Endpoint != "" {
+		opts = append(opts, option.WithEndpoint(s.cfg.Endpoint))
+	}
+	return storage.NewClient(ctx, opts...)
+}
+
+func (s *gcsObjectStore) PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	email, key, err := loadGCSSigningCreds(s.cfg.AccessKey)
+	if err != nil {
+		return "", err
+	}
+	opts := &storage.SignedURLOptions{
+		Scheme:         storage.SigningSchemeV4,
+		Method:         http.MethodGet,
+		GoogleAccessID: email,
+		PrivateKey:     key,
+		Expires:        time.Now().Add(time.Duration(lifetimeSecs) * time.Second),
+	}
+	if v := responseOverrides["response-content-type"]; v != "" {
+		opts.QueryParameters = map[string][]string{"response-content-type": {v}}
+	}
+	return storage.SignedURL(bucketName, objectKey, opts)
+}
+
+func (s *gcsObjectStore) PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	email, key, err := loadGCSSigningCreds(s.cfg.AccessKey)
+	if err != nil {
+		return "", err
+	}
+	return storage.SignedURL(bucketName, objectKey, &storage.SignedURLOptions{
+		Scheme:         storage.SigningSchemeV4,
+		Method:         http.MethodPut,
+		GoogleAccessID: email,
+		PrivateKey:     key,
+		Expires:        time.Now().Add(time.Duration(lifetimeSecs) * time.Second),
+	})
+}
+
+func (s *gcsObjectStore) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	obj := client.Bucket(bucketName).Object(objectKey)
+	reader, err := obj.NewReader(ctx)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	return reader, ObjectInfo{
+		Key:          objectKey,
+		Size:         reader.Attrs.Size,
+		ContentType:  reader.Attrs.ContentType,
+		LastModified: reader.Attrs.LastModified,
+	}, nil
+}
+
+func (s *gcsObjectStore) PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	writer := client.Bucket(bucketName).Object(objectKey).NewWriter(ctx)
+	writer.ContentType = opts.ContentType
+	writer.Metadata = opts.Metadata
+	if _, err := io.Copy(writer, body); err != nil {
+		writer.Close()
+		return "", err
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+	return writer.Attrs().Etag, nil
+}
+
+func (s *gcsObjectStore) StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	attrs, err := client.Bucket(bucketName).Object(objectKey).Attrs(ctx)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{
+		Key:          attrs.Name,
+		Size:         attrs.Size,
+		ETag:         attrs.Etag,
+		ContentType:  attrs.ContentType,
+		LastModified: attrs.Updated,
+	}, nil
+}
+
+func (s *gcsObjectStore) ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	client, err := s.client(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var infos []ObjectInfo
+	it := client.Bucket(bucketName).Objects(ctx, &storage.Query{Prefix: prefix})
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, ObjectInfo{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			ETag:         attrs.Etag,
+			ContentType:  attrs.ContentType,
+			LastModified: attrs.Updated,
+		})
+	}
+	return infos, nil
+}
+
+func (s *gcsObjectStore) DeleteObject(ctx context.Context, bucketName, objectKey string) error {
+	client, err := s.client(ctx)
+	if err != nil {
+		return err
+	}
+	return client.Bucket(bucketName).Object(objectKey).Delete(ctx)
+}
+
+// loadGCSSigningCreds reads the client_email/private_key pair out of a
+// service-account JSON key file, the only way to V4-sign a URL without
+// making a live call to the IAM credentials API.
+func loadGCSSigningCreds(keyFilePath string) (string, []byte, error) {
+	raw, err := os.ReadFile(keyFilePath)
+	if err != nil {
+		return "", nil, fmt.Errorf("reading GCS service account key: %w", err)
+	}
+	var creds struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+	}
+	if err := json.Unmarshal(raw, &creds); err != nil {
+		return "", nil, fmt.Errorf("parsing GCS service account key: %w", err)
+	}
+	return creds.ClientEmail, []byte(creds.PrivateKey), nil
+}
+
+// --- Azure Blob Storage -------------------------------------------------------
+
+// azureObjectStore authenticates with a shared key, for which the Azure SDK
+// needs the storage account name alongside the key. BackendConfig has no
+// Azure-specific field for that, so cfg.Region (otherwise unused by this
+// backend) carries the account name instead.
+type azureObjectStore struct {
+	cfg BackendConfig
+}
+
+func (s *azureObjectStore) client() (*azblob.Client, error) {
+	cred, err := azblob.NewSharedKeyCredential(s.cfg.Region, s.cfg.SecretKey)
+	if err != nil {
+		return nil, err
+	}
+	return azblob.NewClientWithSharedKeyCredential(s.cfg.Endpoint, cred, nil)
+}
+
+func (s *azureObjectStore) presignedURL(bucketName, objectKey string, lifetimeSecs int64, perms sas.BlobPermissions) (string, error) {
+	cred, err := azblob.NewSharedKeyCredential(s.cfg.Region, s.cfg.SecretKey)
+	if err != nil {
+		return "", err
+	}
+	sasURL, err := sas.BlobSignatureValues{
+		Protocol:      sas.ProtocolHTTPS,
+		StartTime:     time.Now().Add(-5 * time.Minute),
+		ExpiryTime:    time.Now().Add(time.Duration(lifetimeSecs) * time.Second),
+		Permissions:   perms.String(),
+		ContainerName: bucketName,
+		BlobName:      objectKey,
+	}.SignWithSharedKey(cred)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s/%s?%s", s.cfg.Endpoint, bucketName, objectKey, sasURL.Encode()), nil
+}
+
+func (s *azureObjectStore) PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	return s.presignedURL(bucketName, objectKey, lifetimeSecs, sas.BlobPermissions{Read: true})
+}
+
+func (s *azureObjectStore) PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	return s.presignedURL(bucketName, objectKey, lifetimeSecs, sas.BlobPermissions{Write: true, Create: true})
+}
+
+func (s *azureObjectStore) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	resp, err := client.DownloadStream(ctx, bucketName, objectKey, nil)
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	info := ObjectInfo{Key: objectKey}
+	if resp.ContentLength != nil {
+		info.Size = *resp.ContentLength
+	}
+	if resp.ETag != nil {
+		info.ETag = string(*resp.ETag)
+	}
+	if resp.ContentType != nil {
+		info.ContentType = *resp.ContentType
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+	return resp.Body, info, nil
+}
+
+func (s *azureObjectStore) PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error) {
+	client, err := s.client()
+	if err != nil {
+		return "", err
+	}
+	resp, err := client.UploadStream(ctx, bucketName, objectKey, body, nil)
+	if err != nil {
+		return "", err
+	}
+	if resp.ETag != nil {
+		return string(*resp.ETag), nil
+	}
+	return "", nil
+}
+
+func (s *azureObjectStore) StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	resp, err := client.ServiceClient().NewContainerClient(bucketName).NewBlobClient(objectKey).GetProperties(ctx, nil)
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	info := ObjectInfo{Key: objectKey}
+	if resp.ContentLength != nil {
+		info.Size = *resp.ContentLength
+	}
+	if resp.ETag != nil {
+		info.ETag = string(*resp.ETag)
+	}
+	if resp.LastModified != nil {
+		info.LastModified = *resp.LastModified
+	}
+	return info, nil
+}
+
+func (s *azureObjectStore) ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	client, err := s.client()
+	if err != nil {
+		return nil, err
+	}
+	var infos []ObjectInfo
+	pager := client.NewListBlobsFlatPager(bucketName, &azblob.ListBlobsFlatOptions{Prefix: &prefix})
+	for pager.More() {
+		page, err := pager.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, blob := range page.Segment.BlobItems {
+			info := ObjectInfo{}
+			if blob.Name != nil {
+				info.Key = *blob.Name
+			}
+			if blob.Properties != nil {
+				if blob.Properties.ContentLength != nil {
+					info.Size = *blob.Properties.ContentLength
+				}
+				if blob.Properties.ETag != nil {
+					info.ETag = string(*blob.Properties.ETag)
+				}
+				if blob.Properties.LastModified != nil {
+					info.LastModified = *blob.Properties.LastModified
+				}
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+func (s *azureObjectStore) DeleteObject(ctx context.Context, bucketName, objectKey string) error {
+	client, err := s.client()
+	if err != nil {
+		return err
+	}
+	_, err = client.DeleteBlob(ctx, bucketName, objectKey, nil)
+	return err
+}
+
+// --- local filesystem (tests / local dev) -------------------------------------
+
+// fsObjectStore roots every bucket at cfg.Endpoint/<bucketName> on the local
+// disk. It exists so handlers and tests can exercise the ObjectStore
+// interface without a real cloud backend.
+type fsObjectStore struct {
+	cfg BackendConfig
+}
+
+func (s *fsObjectStore) path(bucketName, objectKey string) string {
+	return s.cfg.Endpoint + "/" + bucketName + "/" + objectKey
+}
+
+func (s *fsObjectStore) PresignGet(bucketName, objectKey string, lifetimeSecs int64, responseOverrides map[string]string) (string, error) {
+	return "", fmt.Errorf("presigned URLs are not supported by the filesystem backend")
+}
+
+func (s *fsObjectStore) PresignPut(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	return "", fmt.Errorf("presigned URLs are not supported by the filesystem backend")
+}
+
+func (s *fsObjectStore) GetObject(ctx context.Context, bucketName, objectKey string) (io.ReadCloser, ObjectInfo, error) {
+	f, err := os.Open(s.path(bucketName, objectKey))
+	if err != nil {
+		return nil, ObjectInfo{}, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, ObjectInfo{}, err
+	}
+	return f, ObjectInfo{Key: objectKey, Size: stat.Size(), LastModified: stat.ModTime()}, nil
+}
+
+func (s *fsObjectStore) PutObject(ctx context.Context, bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (string, error) {
+	path := s.path(bucketName, objectKey)
+	if err := os.MkdirAll(path[:strings.LastIndex(path, "/")], 0o755); err != nil {
+		return "", err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(f, body); err != nil {
+		return "", err
+	}
+	return "", nil
+}
+
+func (s *fsObjectStore) StatObject(ctx context.Context, bucketName, objectKey string) (ObjectInfo, error) {
+	stat, err := os.Stat(s.path(bucketName, objectKey))
+	if err != nil {
+		return ObjectInfo{}, err
+	}
+	return ObjectInfo{Key: objectKey, Size: stat.Size(), LastModified: stat.ModTime()}, nil
+}
+
+func (s *fsObjectStore) ListObjects(ctx context.Context, bucketName, prefix string) ([]ObjectInfo, error) {
+	entries, err := os.ReadDir(s.cfg.Endpoint + "/" + bucketName)
+	if err != nil {
+		return nil, err
+	}
+	var infos []ObjectInfo
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		infos = append(infos, ObjectInfo{Key: entry.Name(), Size: info.Size(), LastModified: info.ModTime()})
+	}
+	return infos, nil
+}
+
+func (s *fsObjectStore) DeleteObject(ctx context.Context, bucketName, objectKey string) error {
+	return os.Remove(s.path(bucketName, objectKey))
+}
+
+// --- HTTP routing -------------------------------------------------------------
+
+// extractObjectStoreSegments splits a /object/{backend}/{bucket}/{key...}
+// path (with the mount prefix already trimmed) into its three parts.
+func extractObjectStoreSegments(path string) (backendName, bucketName, objectKey string) {
+	segments := strings.SplitN(path, "/", 3)
+	backendName = segments[0]
+	if len(segments) > 1 {
+		bucketName = segments[1]
+	}
+	if len(segments) > 2 {
+		objectKey = segments[2]
+	}
+	return backendName, bucketName, objectKey
+}
+
+// handleObjectStore serves /object/{backend}/{bucket}/{key}, dispatching to
+// whichever ObjectStore the {backend} segment names so one proxy can front
+// multiple heterogeneous stores at once.
+func handleObjectStore(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/object/")
+	backendName, bucketName, objectKey := extractObjectStoreSegments(path)
+
+	store, err := getBackend(backendName)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		body, info, err := store.GetObject(r.Context(), bucketName, objectKey)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error getting object: %v", err), http.StatusBadGateway)
+			return
+		}
+		defer body.Close()
+		if info.ContentType != "" {
+			w.Header().Set("Content-Type", info.ContentType)
+		}
+		if info.ETag != "" {
+			w.Header().Set("ETag", info.ETag)
+		}
+		io.Copy(w, body)
+
+	case http.MethodPut:
+		opts := extractUploadOptions(r)
+		etag, err := store.PutObject(r.Context(), bucketName, objectKey, r.Body, r.ContentLength, opts)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Error uploading object: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.Header().Set("ETag", etag)
+
+	case http.MethodDelete:
+		if err := store.DeleteObject(r.Context(), bucketName, objectKey); err != nil {
+			http.Error(w, fmt.Sprintf("Error deleting object: %v", err), http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		http.Error(w, "method not supported", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+func newFSStoreForTest(t *testing.T) *fsObjectStore {
+	t.Helper()
+	dir := t.TempDir()
+	return &fsObjectStore{cfg: BackendConfig{Name: "test", ServiceName: "fs", Endpoint: dir}}
+}
+
+func TestFSObjectStorePutGetRoundTrip(t *testing.T) {
+	store := newFSStoreForTest(t)
+	ctx := context.Background()
+	want := []byte("hello object storage")
+
+	if _, err := store.PutObject(ctx, "bucket", "dir/file.txt", bytes.NewReader(want), int64(len(want)), uploadOptions{}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	body, info, err := store.GetObject(ctx, "bucket", "dir/file.txt")
+	if err != nil {
+		t.Fatalf("GetObject: %v", err)
+	}
+	defer body.Close()
+
+	got, err := io.ReadAll(body)
+	if err != nil {
+		t.Fatalf("reading object body: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("round-tripped content = %q, want %q", got, want)
+	}
+	if info.Size != int64(len(want)) {
+		t.Errorf("info.Size = %d, want %d", info.Size, len(want))
+	}
+}
+
+func TestFSObjectStoreStatAndDelete(t *testing.T) {
+	store := newFSStoreForTest(t)
+	ctx := context.Background()
+	data := []byte("stat me")
+
+	if _, err := store.PutObject(ctx, "bucket", "key", bytes.NewReader(data), int64(len(data)), uploadOptions{}); err != nil {
+		t.Fatalf("PutObject: %v", err)
+	}
+
+	info, err := store.StatObject(ctx, "bucket", "key")
+	if err != nil {
+		t.Fatalf("StatObject: %v", err)
+	}
+	if info.Size != int64(len(data)) {
+		t.Errorf("StatObject size = %d, want %d", info.Size, len(data))
+	}
+
+	if err := store.DeleteObject(ctx, "bucket", "key"); err != nil {
+		t.Fatalf("DeleteObject: %v", err)
+	}
+	if _, err := store.StatObject(ctx, "bucket", "key"); !os.IsNotExist(err) {
+		t.Errorf("StatObject after delete: got err %v, want os.IsNotExist", err)
+	}
+}
+
+func TestFSObjectStoreListObjectsFiltersByPrefix(t *testing.T) {
+	store := newFSStoreForTest(t)
+	ctx := context.Background()
+
+	for _, key := range []string{"report-jan.csv", "report-feb.csv", "notes.txt"} {
+		if _, err := store.PutObject(ctx, "bucket", key, bytes.NewReader([]byte("x")), 1, uploadOptions{}); err != nil {
+			t.Fatalf("PutObject(%v): %v", key, err)
+		}
+	}
+
+	infos, err := store.ListObjects(ctx, "bucket", "report-")
+	if err != nil {
+		t.Fatalf("ListObjects: %v", err)
+	}
+	if len(infos) != 2 {
+		t.Fatalf("ListObjects returned %d entries, want 2: %+v", len(infos), infos)
+	}
+}
+
+func TestNewObjectStoreIsCaseInsensitiveOnServiceName(t *testing.T) {
+	for _, name := range []string{"fs", "FS", "Fs"} {
+		store, err := newObjectStore(BackendConfig{ServiceName: name, Endpoint: t.TempDir()})
+		if err != nil {
+			t.Fatalf("newObjectStore(%q): %v", name, err)
+		}
+		if _, ok := store.(*fsObjectStore); !ok {
+			t.Errorf("newObjectStore(%q) = %T, want *fsObjectStore", name, store)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// selectRequest is the JSON body accepted by /select/{bucket}/{key}.
+type selectRequest struct {
+	Expression          string `json:"expression"`
+	InputSerialization  string `json:"inputSerialization"`  // CSV, JSON, Parquet
+	OutputSerialization string `json:"outputSerialization"` // CSV, JSON
+}
+
+// handleSelect serves /select/{bucket}/{key}: a SQL expression over an
+// object's rows, streamed back record-by-record instead of buffering the
+// whole result. S3/R2 delegate to the native SelectObjectContent API;
+// every other backend falls back to selectLocal's restricted SQL evaluator.
+func handleSelect(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/select/")
+	bucketName, objectKey, ok := extractBucketAndKey(path)
+	if !ok {
+		http.Error(w, "expected /select/{bucket}/{key}", http.StatusBadRequest)
+		return
+	}
+
+	var req selectRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Error parsing select request: %v", err), http.StatusBadRequest)
+		return
+	}
+	if req.InputSerialization == "" {
+		req.InputSerialization = "CSV"
+	}
+	if req.OutputSerialization == "" {
+		req.OutputSerialization = "JSON"
+	}
+
+	sw := &selectStreamWriter{ResponseWriter: w}
+	flusher, _ := w.(http.Flusher)
+	w.Header().Set("Content-Type", "application/jsonlines")
+
+	if strings.EqualFold(globalConfig.ServiceName, "S3") || strings.EqualFold(globalConfig.ServiceName, "R2") {
+		if err := selectS3(r.Context(), bucketName, objectKey, req, sw, flusher); err != nil {
+			writeSelectError(sw, flusher, fmt.Errorf("running S3 Select: %w", err))
+		}
+		return
+	}
+
+	if strings.EqualFold(req.InputSerialization, "Parquet") {
+		http.Error(w, "Parquet input is only supported against the S3/R2 backend", http.StatusNotImplemented)
+		return
+	}
+
+	if err := selectLocal(bucketName, objectKey, req, sw, flusher); err != nil {
+		writeSelectError(sw, flusher, fmt.Errorf("running select: %w", err))
+	}
+}
+
+// selectStreamWriter wraps the response writer so handleSelect can tell,
+// after a selectS3/selectLocal failure, whether any rows were already
+// written to the client.
+type selectStreamWriter struct {
+	http.ResponseWriter
+	wrote bool
+}
+
+func (w *selectStreamWriter) Write(p []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(p)
+	if n > 0 {
+		w.wrote = true
+	}
+	return n, err
+}
+
+// writeSelectError reports a selectS3/selectLocal failure to the client. If
+// the stream hasn't produced any rows yet, the 200 status line hasn't gone
+// out, so a normal HTTP error still reaches the client cleanly. Once
+// streaming has started, the status and any prior JSON-lines rows are
+// already on the wire; re-calling http.Error there would both fail to change
+// the status code and append a plain-text message onto a partial JSON-lines
+// body, so instead this appends one final JSON-lines record marking the
+// stream as failed.
+func writeSelectError(w *selectStreamWriter, flusher http.Flusher, err error) {
+	log.Printf("Error running select: %v\n", err)
+	if !w.wrote {
+		http.Error(w, fmt.Sprintf("Error running select: %v", err), http.StatusBadGateway)
+		return
+	}
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// selectS3 streams SelectObjectContent Records events straight to w as they
+// arrive, so the client never waits for the whole result to buffer.
+func selectS3(ctx context.Context, bucketName, objectKey string, req selectRequest, w http.ResponseWriter, flusher http.Flusher) error {
+	client, err := newS3Client(legacyBackendConfig())
+	if err != nil {
+		return err
+	}
+
+	inputSerialization := &types.InputSerialization{}
+	switch strings.ToUpper(req.InputSerialization) {
+	case "JSON":
+		inputSerialization.JSON = &types.JSONInput{Type: types.JSONTypeLines}
+	case "PARQUET":
+		inputSerialization.Parquet = &types.ParquetInput{}
+	default:
+		inputSerialization.CSV = &types.CSVInput{FileHeaderInfo: types.FileHeaderInfoUse}
+	}
+
+	outputSerialization := &types.OutputSerialization{}
+	if strings.EqualFold(req.OutputSerialization, "CSV") {
+		outputSerialization.CSV = &types.CSVOutput{}
+	} else {
+		outputSerialization.JSON = &types.JSONOutput{}
+	}
+
+	output, err := client.SelectObjectContent(ctx, &s3.SelectObjectContentInput{
+		Bucket:              aws.String(bucketName),
+		Key:                 aws.String(objectKey),
+		Expression:          aws.String(req.Expression),
+		ExpressionType:      types.ExpressionTypeSql,
+		InputSerialization:  inputSerialization,
+		OutputSerialization: outputSerialization,
+	})
+	if err != nil {
+		return err
+	}
+	defer output.GetStream().Close()
+
+	for event := range output.GetStream().Events() {
+		switch e := event.(type) {
+		case *types.SelectObjectContentEventStreamMemberRecords:
+			w.Write(e.Value.Payload)
+			w.Write([]byte("\n"))
+			if flusher != nil {
+				flusher.Flush()
+			}
+		case *types.SelectObjectContentEventStreamMemberStats, *types.SelectObjectContentEventStreamMemberEnd:
+			// no per-row payload to relay
+		}
+	}
+	return output.GetStream().Err()
+}
+
+// parsedSelect is the restricted "SELECT <cols> FROM S3Object [WHERE <col>
+// <op> <value>]" grammar selectLocal understands.
+type parsedSelect struct {
+	Columns []string // nil means "*"
+	Where   *selectCondition
+}
+
+type selectCondition struct {
+	Column   string
+	Operator string
+	Value    string
+}
+
+// findKeyword returns the index of the first standalone, case-insensitive
+// occurrence of keyword in s, skipping matches that are part of a longer
+// identifier (e.g. "FROM" inside "from_date"), or -1 if there is none.
+func findKeyword(s, keyword string) int {
+	upper := strings.ToUpper(s)
+	keyword = strings.ToUpper(keyword)
+
+	searchFrom := 0
+	for {
+		i := strings.Index(upper[searchFrom:], keyword)
+		if i == -1 {
+			return -1
+		}
+		idx := searchFrom + i
+
+		boundaryBefore := idx == 0 || !isIdentByte(upper[idx-1])
+		afterIdx := idx + len(keyword)
+		boundaryAfter := afterIdx == len(upper) || !isIdentByte(upper[afterIdx])
+		if boundaryBefore && boundaryAfter {
+			return idx
+		}
+		searchFrom = idx + 1
+	}
+}
+
+// isIdentByte reports whether b can appear in a SQL identifier.
+func isIdentByte(b byte) bool {
+	return b == '_' || (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9')
+}
+
+// parseSelectExpression parses the small subset of SQL that S3 Select's
+// MinIO-incompatible backends need to support: column projection and a
+// single equality/comparison WHERE clause.
+func parseSelectExpression(expression string) (parsedSelect, error) {
+	var parsed parsedSelect
+
+	selectIdx := findKeyword(expression, "SELECT")
+	fromIdx := findKeyword(expression, "FROM")
+	if selectIdx == -1 || fromIdx == -1 || fromIdx < selectIdx {
+		return parsed, fmt.Errorf("expected \"SELECT ... FROM S3Object\", got %q", expression)
+	}
+
+	columnsPart := strings.TrimSpace(expression[selectIdx+len("SELECT") : fromIdx])
+	if columnsPart != "*" {
+		for _, col := range strings.Split(columnsPart, ",") {
+			parsed.Columns = append(parsed.Columns, strings.TrimSpace(col))
+		}
+	}
+
+	whereIdx := findKeyword(expression, "WHERE")
+	if whereIdx == -1 {
+		return parsed, nil
+	}
+
+	wherePart := strings.TrimSpace(expression[whereIdx+len("WHERE"):])
+	for _, op := range []string{"!=", "<=", ">=", "=", "<", ">"} {
+		if i := strings.Index(wherePart, op); i != -1 {
+			parsed.Where = &selectCondition{
+				Column:   strings.TrimSpace(wherePart[:i]),
+				Operator: op,
+				Value:    strings.Trim(strings.TrimSpace(wherePart[i+len(op):]), "'\""),
+			}
+			return parsed, nil
+		}
+	}
+
+	return parsed, fmt.Errorf("unsupported WHERE clause %q", wherePart)
+}
+
+// evalCondition reports whether row satisfies cond, comparing numerically
+// when both sides parse as floats and lexically otherwise.
+func evalCondition(row map[string]string, cond *selectCondition) bool {
+	if cond == nil {
+		return true
+	}
+	actual, ok := row[cond.Column]
+	if !ok {
+		return false
+	}
+
+	actualNum, actualErr := strconv.ParseFloat(actual, 64)
+	expectedNum, expectedErr := strconv.ParseFloat(cond.Value, 64)
+	if actualErr == nil && expectedErr == nil {
+		return compareNumbers(actualNum, expectedNum, cond.Operator)
+	}
+	return compareStrings(actual, cond.Value, cond.Operator)
+}
+
+func compareNumbers(a, b float64, operator string) bool {
+	switch operator {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func compareStrings(a, b, operator string) bool {
+	switch operator {
+	case "=":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case ">":
+		return a > b
+	case "<=":
+		return a <= b
+	case ">=":
+		return a >= b
+	}
+	return false
+}
+
+func projectRow(row map[string]string, columns []string) map[string]string {
+	if columns == nil {
+		return row
+	}
+	projected := make(map[string]string, len(columns))
+	for _, col := range columns {
+		projected[col] = row[col]
+	}
+	return projected
+}
+
+// selectLocal implements S3 Select for backends (MinIO) that don't support
+// it natively: it streams the object from the backend, parses it a row at a
+// time, and writes each matching row to w as it's evaluated instead of
+// buffering the whole object or result set in memory. Rows are always
+// written as JSON lines; req.OutputSerialization is not consulted here.
+func selectLocal(bucketName, objectKey string, req selectRequest, w http.ResponseWriter, flusher http.Flusher) error {
+	parsed, err := parseSelectExpression(req.Expression)
+	if err != nil {
+		return err
+	}
+
+	store, err := newObjectStore(legacyBackendConfig())
+	if err != nil {
+		return err
+	}
+	body, _, err := store.GetObject(context.Background(), bucketName, objectKey)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
+
+	encoder := json.NewEncoder(w)
+
+	switch strings.ToUpper(req.InputSerialization) {
+	case "CSV":
+		reader := csv.NewReader(bufio.NewReader(body))
+		header, err := reader.Read()
+		if err != nil {
+			return fmt.Errorf("reading CSV header: %w", err)
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("reading CSV row: %w", err)
+			}
+			row := make(map[string]string, len(header))
+			for i, col := range header {
+				if i < len(record) {
+					row[col] = record[i]
+				}
+			}
+			if !evalCondition(row, parsed.Where) {
+				continue
+			}
+			if err := encoder.Encode(projectRow(row, parsed.Columns)); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return nil
+
+	case "JSON":
+		scanner := bufio.NewScanner(body)
+		for scanner.Scan() {
+			line := bytes.TrimSpace(scanner.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var fields map[string]interface{}
+			if err := json.Unmarshal(line, &fields); err != nil {
+				return fmt.Errorf("parsing JSON line: %w", err)
+			}
+			row := make(map[string]string, len(fields))
+			for k, v := range fields {
+				row[k] = fmt.Sprintf("%v", v)
+			}
+			if !evalCondition(row, parsed.Where) {
+				continue
+			}
+			if err := encoder.Encode(projectRow(row, parsed.Columns)); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+		return scanner.Err()
+	}
+
+	return fmt.Errorf("unsupported inputSerialization %q", req.InputSerialization)
+}
@@ -0,0 +1,60 @@
+package main
+
+import "testing"
+
+func TestParseSelectExpressionColumnNameContainingKeyword(t *testing.T) {
+	parsed, err := parseSelectExpression("SELECT from_date, whereabouts FROM S3Object")
+	if err != nil {
+		t.Fatalf("parseSelectExpression: %v", err)
+	}
+	want := []string{"from_date", "whereabouts"}
+	if len(parsed.Columns) != len(want) {
+		t.Fatalf("Columns = %v, want %v", parsed.Columns, want)
+	}
+	for i, col := range want {
+		if parsed.Columns[i] != col {
+			t.Errorf("Columns[%d] = %q, want %q", i, parsed.Columns[i], col)
+		}
+	}
+	if parsed.Where != nil {
+		t.Errorf("Where = %+v, want nil", parsed.Where)
+	}
+}
+
+func TestParseSelectExpressionWhereOnColumnContainingKeyword(t *testing.T) {
+	parsed, err := parseSelectExpression("SELECT * FROM S3Object WHERE from_ip = '10.0.0.1'")
+	if err != nil {
+		t.Fatalf("parseSelectExpression: %v", err)
+	}
+	if parsed.Where == nil {
+		t.Fatal("Where = nil, want a condition")
+	}
+	if parsed.Where.Column != "from_ip" || parsed.Where.Operator != "=" || parsed.Where.Value != "10.0.0.1" {
+		t.Errorf("Where = %+v, want {from_ip = 10.0.0.1}", parsed.Where)
+	}
+}
+
+func TestParseSelectExpressionStar(t *testing.T) {
+	parsed, err := parseSelectExpression("SELECT * FROM S3Object")
+	if err != nil {
+		t.Fatalf("parseSelectExpression: %v", err)
+	}
+	if parsed.Columns != nil {
+		t.Errorf("Columns = %v, want nil for *", parsed.Columns)
+	}
+}
+
+func TestParseSelectExpressionMissingFrom(t *testing.T) {
+	if _, err := parseSelectExpression("SELECT performant"); err == nil {
+		t.Error("expected an error for an expression with no FROM clause")
+	}
+}
+
+func TestFindKeywordSkipsSubstringMatches(t *testing.T) {
+	if idx := findKeyword("SELECT from_date FROM S3Object", "FROM"); idx != len("SELECT from_date ") {
+		t.Errorf("findKeyword = %d, want index of the standalone FROM", idx)
+	}
+	if idx := findKeyword("SELECT * FROM S3Object", "WHERE"); idx != -1 {
+		t.Errorf("findKeyword = %d, want -1 when WHERE is absent", idx)
+	}
+}
@@ -0,0 +1,212 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// defaultMultipartThreshold is the object size above which S3/R2 uploads
+	// are switched to the resumable multipart path.
+	defaultMultipartThreshold = 64 * 1024 * 1024 // 64 MiB
+	defaultPartSize           = 16 * 1024 * 1024 // 16 MiB
+	defaultUploadConcurrency  = 4
+)
+
+// metaHeaderPrefix is the header prefix clients use to attach S3 user-metadata.
+const metaHeaderPrefix = "X-Amz-Meta-"
+
+// sseHeaders are the server-side encryption headers passed through verbatim
+// to the upstream S3/R2 PutObject/CreateMultipartUpload calls (the SSE-C ones
+// to UploadPart as well). The MinIO upload paths do not send them.
+var sseHeaders = []string{
+	"X-Amz-Server-Side-Encryption",
+	"X-Amz-Server-Side-Encryption-Aws-Kms-Key-Id",
+	"X-Amz-Server-Side-Encryption-Customer-Algorithm",
+	"X-Amz-Server-Side-Encryption-Customer-Key",
+	"X-Amz-Server-Side-Encryption-Customer-Key-Md5",
+}
+
+// Uploader wraps the minio-go and AWS SDK v2 upload paths behind one API.
+// The S3 branch switches to multipart uploads once an object crosses
+// MultipartThreshold (or immediately, if the caller supplies an Upload-Id to
+// resume); the MinIO branch does not consult MultipartThreshold. Exactly
+// one of MinioClient/S3Client is set, matching which ServiceName the proxy
+// was configured for. The S3 branch delegates to the same putObjectS3 that
+// backs s3ObjectStore.PutObject (see objectstore.go), so opts.UploadID and
+// opts.SSEHeaders behave identically whether a client uploads through
+// /upload or /object.
+type Uploader struct {
+	MinioClient        *minio.Client
+	S3Client           *s3.Client
+	MultipartThreshold int64
+}
+
+// NewUploader builds an Uploader for the configured ServiceName.
+func NewUploader() (*Uploader, error) {
+	if strings.EqualFold(globalConfig.ServiceName, "minio") {
+		minioClient, err := newMinioClient(legacyBackendConfig())
+		if err != nil {
+			return nil, err
+		}
+		return &Uploader{MinioClient: minioClient, MultipartThreshold: defaultMultipartThreshold}, nil
+	} else if strings.EqualFold(globalConfig.ServiceName, "S3") || strings.EqualFold(globalConfig.ServiceName, "R2") {
+		s3Client, err := newS3Client(legacyBackendConfig())
+		if err != nil {
+			return nil, err
+		}
+		return &Uploader{S3Client: s3Client, MultipartThreshold: defaultMultipartThreshold}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported object storage service")
+}
+
+// uploadOptions carries the per-request knobs extracted from the client's
+// upload headers.
+type uploadOptions struct {
+	ContentType string
+	Metadata    map[string]string
+	SSEHeaders  map[string]string
+	UploadID    string
+}
+
+// PutObject uploads body to bucketName/objectKey, returning its ETag and,
+// for S3/R2 uploads that went through putObjectS3Multipart, the Upload-Id
+// the client should echo back on retry. minio-go's client switches to
+// multipart internally based on PartSize (defaultPartSize), not
+// MultipartThreshold, so that path stays a single call; it has no resumable-upload API to hook
+// opts.UploadID into, so a non-empty UploadID there is an error.
+func (u *Uploader) PutObject(bucketName, objectKey string, body io.Reader, size int64, opts uploadOptions) (etag, uploadID string, err error) {
+	if u.MinioClient != nil {
+		if opts.UploadID != "" {
+			return "", "", fmt.Errorf("resumable uploads via Upload-Id are only supported against the S3/R2 backend")
+		}
+		info, err := u.MinioClient.PutObject(context.Background(), bucketName, objectKey, body, size, minio.PutObjectOptions{
+			ContentType:  opts.ContentType,
+			UserMetadata: opts.Metadata,
+			PartSize:     defaultPartSize,
+			NumThreads:   defaultUploadConcurrency,
+		})
+		if err != nil {
+			return "", "", fmt.Errorf("minio PutObject %v:%v: %w", bucketName, objectKey, err)
+		}
+		return info.ETag, "", nil
+	}
+
+	if u.S3Client != nil {
+		return putObjectS3(context.TODO(), u.S3Client, bucketName, objectKey, body, size, opts, u.MultipartThreshold)
+	}
+
+	return "", "", fmt.Errorf("uploader has no backend configured")
+}
+
+// PresignPutObject mirrors getPresignedURL for uploads, symmetrical to the
+// existing GET presign path.
+func (u *Uploader) PresignPutObject(bucketName, objectKey string, lifetimeSecs int64) (string, error) {
+	lifetime := time.Duration(lifetimeSecs) * time.Second
+
+	if u.MinioClient != nil {
+		respURL, err := u.MinioClient.PresignedPutObject(context.Background(), bucketName, objectKey, lifetime)
+		if err != nil {
+			return "", err
+		}
+		return respURL.String(), nil
+	}
+
+	if u.S3Client != nil {
+		presignClient := s3.NewPresignClient(u.S3Client)
+		request, err := presignClient.PresignPutObject(context.TODO(), &s3.PutObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(objectKey),
+		}, func(opts *s3.PresignOptions) {
+			opts.Expires = lifetime
+		})
+		if err != nil {
+			return "", err
+		}
+		return request.URL, nil
+	}
+
+	return "", fmt.Errorf("uploader has no backend configured")
+}
+
+// extractUploadOptions reads the upload headers off r into an uploadOptions.
+func extractUploadOptions(r *http.Request) uploadOptions {
+	opts := uploadOptions{
+		Metadata:    make(map[string]string),
+		SSEHeaders:  make(map[string]string),
+		ContentType: r.Header.Get("Content-Type"),
+		UploadID:    r.Header.Get("Upload-Id"),
+	}
+	if opts.ContentType == "" {
+		opts.ContentType = mime.TypeByExtension(filepath.Ext(r.URL.Path))
+	}
+
+	for name, values := range r.Header {
+		if len(values) == 0 {
+			continue
+		}
+		if strings.HasPrefix(strings.ToLower(name), strings.ToLower(metaHeaderPrefix)) {
+			key := name[len(metaHeaderPrefix):]
+			opts.Metadata[key] = values[0]
+		}
+	}
+	for _, header := range sseHeaders {
+		if v := r.Header.Get(header); v != "" {
+			opts.SSEHeaders[header] = v
+		}
+	}
+
+	return opts
+}
+
+// handleUpload serves PUT /upload/{bucket}/{key}, streaming the request body
+// straight into the configured backend without buffering it in memory.
+func handleUpload(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "only PUT is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, "/upload/")
+	bucketName, objectKey, ok := extractBucketAndKey(path)
+	if !ok {
+		http.Error(w, "expected /upload/{bucket}/{key}", http.StatusBadRequest)
+		return
+	}
+
+	uploader, err := NewUploader()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error creating uploader: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	opts := extractUploadOptions(r)
+
+	etag, uploadID, err := uploader.PutObject(bucketName, objectKey, r.Body, r.ContentLength, opts)
+	if err != nil {
+		log.Printf("Error uploading object %v:%v (upload-id %v): %v\n", bucketName, objectKey, uploadID, err)
+		if uploadID != "" {
+			w.Header().Set("Upload-Id", uploadID)
+		}
+		http.Error(w, fmt.Sprintf("Error uploading object: %v", err), http.StatusBadGateway)
+		return
+	}
+
+	if uploadID != "" {
+		w.Header().Set("Upload-Id", uploadID)
+	}
+	w.Header().Set("ETag", etag)
+	w.WriteHeader(http.StatusOK)
+}